@@ -0,0 +1,269 @@
+package lokilogger
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/techpartners-asia/lokilogger/internal/retry"
+)
+
+// QueuePolicy controls what happens to a new entry when the batching queue
+// is full.
+type QueuePolicy int
+
+const (
+	// DropOldest evicts the oldest queued entry to make room for the new one.
+	DropOldest QueuePolicy = iota
+	// DropNewest discards the incoming entry and leaves the queue as-is.
+	DropNewest
+	// Block waits for room in the queue, applying backpressure to the caller.
+	Block
+)
+
+// BatchConfig controls how a Core buffers and flushes entries to Loki.
+type BatchConfig struct {
+	// BatchSize is the max number of entries flushed in one push. Defaults to 1000.
+	BatchSize int
+	// BatchBytes is the max total line size (in bytes) flushed in one push. Defaults to 1MB.
+	BatchBytes int
+	// FlushInterval is the longest an entry waits in the queue before being flushed. Defaults to 1s.
+	FlushInterval time.Duration
+	// MaxRetries is the number of retries for a failed push, with exponential backoff and jitter. Defaults to 5.
+	MaxRetries int
+	// QueueSize is the number of entries the queue holds before QueuePolicy kicks in. Defaults to 10000.
+	QueueSize int
+	// QueuePolicy controls what happens when the queue is full. Defaults to DropOldest.
+	QueuePolicy QueuePolicy
+	// OnError, if set, is called whenever a batch push fails after exhausting retries.
+	OnError func(error)
+}
+
+func (c *BatchConfig) setDefaults() {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 1000
+	}
+	if c.BatchBytes <= 0 {
+		c.BatchBytes = 1 << 20 // 1MB
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Second
+	}
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = 5
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 10000
+	}
+}
+
+// queuedEntry is a single Loki log line waiting to be batched.
+type queuedEntry struct {
+	labels map[string]string
+	ts     int64
+	line   string
+}
+
+// batcher collapses queuedEntries sharing a label set into a single Stream
+// with multiple Values, flushing to Loki when BatchSize, BatchBytes, or
+// FlushInterval is reached, and retries failed pushes with exponential
+// backoff and jitter.
+type batcher struct {
+	cfg     BatchConfig
+	push    func(streams []Stream) error
+	queue   chan queuedEntry
+	flushCh chan chan struct{}
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+	dropped uint64
+}
+
+// newBatcher starts the background flush loop and returns a batcher ready
+// to accept entries via enqueue.
+func newBatcher(cfg BatchConfig, push func(streams []Stream) error) *batcher {
+	cfg.setDefaults()
+
+	b := &batcher{
+		cfg:     cfg,
+		push:    push,
+		queue:   make(chan queuedEntry, cfg.QueueSize),
+		flushCh: make(chan chan struct{}),
+		closeCh: make(chan struct{}),
+	}
+
+	b.wg.Add(1)
+	go b.run()
+
+	return b
+}
+
+// enqueue adds an entry to the queue, applying the configured QueuePolicy if
+// the queue is full.
+func (b *batcher) enqueue(e queuedEntry) {
+	switch b.cfg.QueuePolicy {
+	case Block:
+		b.queue <- e
+	case DropNewest:
+		select {
+		case b.queue <- e:
+		default:
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	default: // DropOldest
+		for {
+			select {
+			case b.queue <- e:
+				return
+			default:
+				select {
+				case <-b.queue:
+					atomic.AddUint64(&b.dropped, 1)
+				default:
+				}
+			}
+		}
+	}
+}
+
+// Dropped returns the number of entries discarded because the queue was full.
+func (b *batcher) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// Flush blocks until all entries currently queued have been pushed (or ctx
+// is done).
+func (b *batcher) Flush(ctx context.Context) error {
+	reply := make(chan struct{})
+	select {
+	case b.flushCh <- reply:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.closeCh:
+		return nil
+	}
+
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the flush loop after draining and pushing any queued entries.
+func (b *batcher) Close() error {
+	close(b.closeCh)
+	b.wg.Wait()
+	return nil
+}
+
+func (b *batcher) run() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	pending := map[string]*Stream{}
+	order := make([]string, 0)
+	count := 0
+	bytes := 0
+
+	add := func(e queuedEntry) {
+		key := labelsKey(e.labels)
+		s, ok := pending[key]
+		if !ok {
+			s = &Stream{Stream: e.labels}
+			pending[key] = s
+			order = append(order, key)
+		}
+		s.Values = append(s.Values, []string{formatTimestamp(e.ts), e.line})
+		count++
+		bytes += len(e.line)
+	}
+
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		streams := make([]Stream, 0, len(order))
+		for _, key := range order {
+			streams = append(streams, *pending[key])
+		}
+		b.flushStreams(streams)
+
+		pending = map[string]*Stream{}
+		order = order[:0]
+		count = 0
+		bytes = 0
+	}
+
+	drain := func() {
+		for {
+			select {
+			case e := <-b.queue:
+				add(e)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case e := <-b.queue:
+			add(e)
+			if count >= b.cfg.BatchSize || bytes >= b.cfg.BatchBytes {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case reply := <-b.flushCh:
+			drain()
+			flush()
+			close(reply)
+		case <-b.closeCh:
+			drain()
+			flush()
+			return
+		}
+	}
+}
+
+// flushStreams pushes streams, retrying with exponential backoff and jitter
+// up to MaxRetries before reporting the final error via OnError. When push
+// is backed by a spool.Spool, the spool already retries the delivery
+// attempt internally (see Spool.Push) before returning an error, so these
+// retries only add extra attempts once the spool itself has given up.
+func (b *batcher) flushStreams(streams []Stream) {
+	err := retry.Do(b.cfg.MaxRetries, func() error { return b.push(streams) })
+	if err != nil && b.cfg.OnError != nil {
+		b.cfg.OnError(err)
+	}
+}
+
+func formatTimestamp(unixNano int64) string {
+	return strconv.FormatInt(unixNano, 10)
+}
+
+// labelsKey deterministically serializes a label set so entries sharing the
+// same labels collapse into one Stream regardless of map iteration order.
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(labels[k])
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
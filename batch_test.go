@@ -0,0 +1,148 @@
+package lokilogger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcherEnqueueDropOldest(t *testing.T) {
+	b := &batcher{
+		cfg:   BatchConfig{QueuePolicy: DropOldest},
+		queue: make(chan queuedEntry, 2),
+	}
+
+	for i := 0; i < 5; i++ {
+		b.enqueue(queuedEntry{ts: int64(i)})
+	}
+
+	if got, want := b.Dropped(), uint64(3); got != want {
+		t.Errorf("Dropped() = %d, want %d", got, want)
+	}
+
+	var remaining []int64
+	for {
+		select {
+		case e := <-b.queue:
+			remaining = append(remaining, e.ts)
+			continue
+		default:
+		}
+		break
+	}
+	if len(remaining) != 2 || remaining[0] != 3 || remaining[1] != 4 {
+		t.Errorf("queue retained %v, want [3 4]", remaining)
+	}
+}
+
+func TestBatcherEnqueueDropNewest(t *testing.T) {
+	b := &batcher{
+		cfg:   BatchConfig{QueuePolicy: DropNewest},
+		queue: make(chan queuedEntry, 2),
+	}
+
+	for i := 0; i < 5; i++ {
+		b.enqueue(queuedEntry{ts: int64(i)})
+	}
+
+	if got, want := b.Dropped(), uint64(3); got != want {
+		t.Errorf("Dropped() = %d, want %d", got, want)
+	}
+
+	var remaining []int64
+	for {
+		select {
+		case e := <-b.queue:
+			remaining = append(remaining, e.ts)
+			continue
+		default:
+		}
+		break
+	}
+	if len(remaining) != 2 || remaining[0] != 0 || remaining[1] != 1 {
+		t.Errorf("queue retained %v, want [0 1]", remaining)
+	}
+}
+
+func TestBatcherCollapsesByLabelSet(t *testing.T) {
+	var mu sync.Mutex
+	var got []Stream
+
+	b := newBatcher(BatchConfig{FlushInterval: time.Hour}, func(streams []Stream) error {
+		mu.Lock()
+		got = append(got, streams...)
+		mu.Unlock()
+		return nil
+	})
+	defer b.Close()
+
+	api := map[string]string{"service": "api"}
+	b.enqueue(queuedEntry{labels: api, ts: 1, line: "one"})
+	b.enqueue(queuedEntry{labels: api, ts: 2, line: "two"})
+	b.enqueue(queuedEntry{labels: map[string]string{"service": "worker"}, ts: 3, line: "three"})
+
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 {
+		t.Fatalf("got %d streams, want 2", len(got))
+	}
+	for _, s := range got {
+		if s.Stream["service"] == "api" && len(s.Values) != 2 {
+			t.Errorf("api stream has %d values, want 2", len(s.Values))
+		}
+	}
+}
+
+func TestBatcherRetriesThenSucceeds(t *testing.T) {
+	var attempts int
+	cfg := BatchConfig{FlushInterval: time.Hour, MaxRetries: 3}
+	b := newBatcher(cfg, func(streams []Stream) error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	defer b.Close()
+
+	b.enqueue(queuedEntry{labels: map[string]string{"a": "b"}, ts: 1, line: "x"})
+
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestBatcherReportsErrorAfterExhaustingRetries(t *testing.T) {
+	boom := errors.New("boom")
+	errCh := make(chan error, 1)
+	cfg := BatchConfig{
+		FlushInterval: time.Hour,
+		MaxRetries:    1,
+		OnError:       func(err error) { errCh <- err },
+	}
+	b := newBatcher(cfg, func(streams []Stream) error { return boom })
+	defer b.Close()
+
+	b.enqueue(queuedEntry{labels: map[string]string{"a": "b"}, ts: 1, line: "x"})
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, boom) {
+			t.Errorf("OnError got %v, want %v", err, boom)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnError was never called")
+	}
+}
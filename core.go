@@ -0,0 +1,288 @@
+package lokilogger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/techpartners-asia/lokilogger/sink"
+	"github.com/techpartners-asia/lokilogger/spool"
+)
+
+// PushFormat selects the wire format used to push batches to Loki's
+// /loki/api/v1/push endpoint.
+type PushFormat = sink.PushFormat
+
+const (
+	// FormatJSON pushes batches using the plain JSON structure documented by
+	// Loki's HTTP push API. This is the default.
+	FormatJSON = sink.FormatJSON
+	// FormatProtobuf pushes batches as a Snappy-compressed logproto.PushRequest,
+	// matching Loki's efficient ingestion path (and Prometheus remote-write's
+	// wire conventions). Required for sustained high-volume ingestion and a
+	// prerequisite for structured metadata.
+	FormatProtobuf = sink.FormatProtobuf
+)
+
+// BasicAuth holds HTTP basic auth credentials for the Loki push endpoint.
+type BasicAuth = sink.BasicAuth
+
+// SpoolConfig controls the optional on-disk write-ahead spool. See
+// spool.Config for field documentation.
+type SpoolConfig = spool.Config
+
+// CoreConfig holds the configuration for a Loki-backed zapcore.Core.
+type CoreConfig struct {
+	BaseURL     string
+	Environment string
+	Service     string
+
+	// HTTPClient is used to push encoded entries to Loki. Defaults to a
+	// client with a 10s timeout. Ignored if Sink is set.
+	HTTPClient *http.Client
+
+	// LevelEnabler controls which entries are accepted by the core.
+	// Defaults to zap.InfoLevel and above.
+	LevelEnabler zapcore.LevelEnabler
+
+	// Batch controls how entries are buffered and flushed to Loki. See
+	// BatchConfig for defaults.
+	Batch BatchConfig
+
+	// PushFormat selects the wire format used to push batches. Defaults to
+	// FormatJSON. Ignored if Sink is set.
+	PushFormat PushFormat
+
+	// Transport is used to build the default HTTPClient when one isn't
+	// provided, letting callers add mTLS, a proxy, tracing (otelhttp), or
+	// rate limiting without owning the whole *http.Client. Ignored if
+	// HTTPClient or Sink is set.
+	Transport http.RoundTripper
+
+	// TenantID, if set, is sent as X-Scope-OrgID on every request, as
+	// required by multi-tenant Loki deployments and Grafana Cloud. Ignored
+	// if Sink is set.
+	TenantID string
+
+	// BasicAuth, if set, is applied to every request via req.SetBasicAuth.
+	// Ignored if Sink is set.
+	BasicAuth *BasicAuth
+
+	// BearerToken, if set, is sent as a static "Authorization: Bearer ..."
+	// header on every request. Ignored if Sink is set.
+	BearerToken string
+
+	// BearerTokenFile, if set (and BearerToken is not), is read on startup
+	// and then re-read every BearerTokenRefresh to pick up rotated tokens.
+	// Ignored if Sink is set.
+	BearerTokenFile string
+
+	// BearerTokenRefresh controls how often BearerTokenFile is re-read.
+	// Defaults to 30s.
+	BearerTokenRefresh time.Duration
+
+	// Headers are set on every request after TenantID/BasicAuth/BearerToken,
+	// so they can override the above if needed. Ignored if Sink is set.
+	Headers map[string]string
+
+	// Sink, if set, replaces the default Loki HTTP push sink built from the
+	// fields above — e.g. sink.NewKafka(...) to buffer through Kafka instead.
+	Sink sink.Sink
+
+	// Spool, if Spool.Dir is set, wraps the sink with an on-disk write-ahead
+	// log so queued batches survive a crash or a long sink outage: every
+	// batch is fsync'd to a segment file before the push is attempted, and
+	// unfinished segments are replayed on the next New().
+	Spool SpoolConfig
+}
+
+// Core is a zapcore.Core that renders each entry with a real zap JSON
+// encoder and enqueues it for batched delivery through a sink.Sink, labeling
+// the stream with the entry's level, logger name, and caller. This replaces
+// the hand-rolled field-to-string conversion previously done in sendLog with
+// zap's own encoder, so namespaces, object marshalers, and every other
+// zapcore field type are rendered exactly as they would be on stdout.
+type Core struct {
+	cfg     CoreConfig
+	encoder zapcore.Encoder
+	level   zapcore.LevelEnabler
+	batcher *batcher
+	sink    sink.Sink
+}
+
+// NewCore builds a *Core that ships entries to the Loki instance described
+// by cfg. *Core satisfies zapcore.Core, so it can be composed with other
+// cores (e.g. a stdout core) via zapcore.NewTee:
+//
+//	core := lokilogger.NewCore(cfg)
+//	logger := zap.New(zapcore.NewTee(
+//	    core,
+//	    zapcore.NewCore(consoleEncoder, os.Stdout, zap.InfoLevel),
+//	))
+//
+// Returning the concrete type (rather than the zapcore.Core interface) also
+// gives callers who build their own zap.Logger this way access to Dropped,
+// Flush, and SpoolMetrics, which aren't part of zapcore.Core.
+//
+// Entries are not pushed synchronously: they are enqueued and flushed in
+// batches by a background goroutine per cfg.Batch. Call Sync (or Flush) to
+// drain the queue before shutdown.
+func NewCore(cfg CoreConfig) *Core {
+	return newLokiCore(cfg)
+}
+
+func newLokiCore(cfg CoreConfig) *Core {
+	if cfg.LevelEnabler == nil {
+		cfg.LevelEnabler = zap.InfoLevel
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "" // the timestamp travels as the Loki entry timestamp, not in the line
+
+	s := cfg.Sink
+	if s == nil {
+		s = sink.NewHTTP(sink.HTTPConfig{
+			BaseURL:            cfg.BaseURL,
+			HTTPClient:         cfg.HTTPClient,
+			Transport:          cfg.Transport,
+			Format:             cfg.PushFormat,
+			TenantID:           cfg.TenantID,
+			BasicAuth:          cfg.BasicAuth,
+			BearerToken:        cfg.BearerToken,
+			BearerTokenFile:    cfg.BearerTokenFile,
+			BearerTokenRefresh: cfg.BearerTokenRefresh,
+			Headers:            cfg.Headers,
+			OnError:            cfg.Batch.OnError,
+		})
+	}
+
+	if cfg.Spool.Dir != "" {
+		spooled, err := spool.Wrap(s, cfg.Spool)
+		if err != nil {
+			if cfg.Batch.OnError != nil {
+				cfg.Batch.OnError(fmt.Errorf("failed to enable spool, continuing without it: %w", err))
+			}
+		} else {
+			s = spooled
+		}
+	}
+
+	c := &Core{
+		cfg:     cfg,
+		encoder: zapcore.NewJSONEncoder(encoderConfig),
+		level:   cfg.LevelEnabler,
+		sink:    s,
+	}
+	c.batcher = newBatcher(cfg.Batch, c.pushStreams)
+
+	return c
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *Core) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+// With implements zapcore.Core. Fields are baked into a cloned encoder
+// rather than kept as a slice for Write to append to later, matching zap's
+// own ioCore.With: a shared []zapcore.Field would risk concurrent Writes on
+// different With()-derived cores racing on the same backing array whenever
+// append finds spare capacity.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.encoder = c.encoder.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.encoder)
+	}
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core, encoding entry with the underlying zap
+// encoder and enqueuing the result for batched delivery to the sink.
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+	defer buf.Free()
+
+	labels := map[string]string{
+		"service":     c.cfg.Service,
+		"environment": c.cfg.Environment,
+		"level":       entry.Level.String(),
+	}
+	if entry.LoggerName != "" {
+		labels["logger"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		labels["caller"] = entry.Caller.TrimmedPath()
+	}
+
+	c.batcher.enqueue(queuedEntry{
+		labels: labels,
+		ts:     entry.Time.UnixNano(),
+		line:   buf.String(),
+	})
+
+	return nil
+}
+
+// Sync implements zapcore.Core, blocking until all entries queued so far
+// have been pushed.
+func (c *Core) Sync() error {
+	return c.batcher.Flush(context.Background())
+}
+
+// Flush blocks until all entries queued so far have been pushed, or ctx is done.
+func (c *Core) Flush(ctx context.Context) error {
+	return c.batcher.Flush(ctx)
+}
+
+// Close drains the queue, stops the background flush loop, and closes the sink.
+func (c *Core) Close() error {
+	if err := c.batcher.Close(); err != nil {
+		return err
+	}
+	return c.sink.Close()
+}
+
+// Dropped returns the number of entries discarded because the queue was
+// full, per cfg.Batch.QueuePolicy.
+func (c *Core) Dropped() uint64 {
+	return c.batcher.Dropped()
+}
+
+// SpoolMetrics returns the on-disk spool's current disk usage, and false if
+// no Spool.Dir was configured.
+func (c *Core) SpoolMetrics() (spool.Metrics, bool) {
+	s, ok := c.sink.(*spool.Spool)
+	if !ok {
+		return spool.Metrics{}, false
+	}
+	return s.Metrics(), true
+}
+
+func (c *Core) pushStreams(streams []Stream) error {
+	sinkStreams := make([]sink.Stream, 0, len(streams))
+	for _, s := range streams {
+		values := make([][2]string, 0, len(s.Values))
+		for _, v := range s.Values {
+			values = append(values, [2]string{v[0], v[1]})
+		}
+		sinkStreams = append(sinkStreams, sink.Stream{Labels: s.Stream, Values: values})
+	}
+
+	return c.sink.Push(context.Background(), sinkStreams)
+}
@@ -0,0 +1,97 @@
+// Package logproto implements the minimal subset of Loki's logproto.proto
+// wire format needed to push log streams as protobuf: PushRequest, its
+// StreamAdapter/EntryAdapter, and structured metadata. Field numbers mirror
+// grafana/loki's generated logproto.pb.go, but encoding is done directly
+// with protowire instead of pulling in the full generated client.
+package logproto
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// LabelPairAdapter mirrors logproto.LabelPairAdapter, used for an entry's
+// structured metadata (Loki 2.9+).
+type LabelPairAdapter struct {
+	Name  string
+	Value string
+}
+
+// EntryAdapter mirrors logproto.Entry: a single log line within a Stream.
+type EntryAdapter struct {
+	Timestamp          time.Time
+	Line               string
+	StructuredMetadata []LabelPairAdapter
+}
+
+// StreamAdapter mirrors logproto.Stream: a label set (formatted as a LogQL
+// selector, e.g. `{service="api", level="info"}`) and its entries.
+type StreamAdapter struct {
+	Labels  string
+	Entries []EntryAdapter
+}
+
+// PushRequest mirrors logproto.PushRequest, the message Loki's push API
+// expects as the protobuf request body.
+type PushRequest struct {
+	Streams []StreamAdapter
+}
+
+// Marshal encodes r as a logproto.PushRequest protobuf message.
+func (r *PushRequest) Marshal() []byte {
+	var buf []byte
+	for _, s := range r.Streams {
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, s.marshal())
+	}
+	return buf
+}
+
+func (s *StreamAdapter) marshal() []byte {
+	var buf []byte
+	buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+	buf = protowire.AppendString(buf, s.Labels)
+	for _, e := range s.Entries {
+		buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, e.marshal())
+	}
+	return buf
+}
+
+func (e *EntryAdapter) marshal() []byte {
+	var buf []byte
+	buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+	buf = protowire.AppendBytes(buf, marshalTimestamp(e.Timestamp))
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+	buf = protowire.AppendString(buf, e.Line)
+	for _, md := range e.StructuredMetadata {
+		buf = protowire.AppendTag(buf, 3, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, md.marshal())
+	}
+	return buf
+}
+
+func (p *LabelPairAdapter) marshal() []byte {
+	var buf []byte
+	buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+	buf = protowire.AppendString(buf, p.Name)
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+	buf = protowire.AppendString(buf, p.Value)
+	return buf
+}
+
+// marshalTimestamp encodes t as a google.protobuf.Timestamp submessage
+// (seconds in field 1, nanos in field 2).
+func marshalTimestamp(t time.Time) []byte {
+	var buf []byte
+	if sec := t.Unix(); sec != 0 {
+		buf = protowire.AppendTag(buf, 1, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, uint64(sec))
+	}
+	if nsec := t.Nanosecond(); nsec != 0 {
+		buf = protowire.AppendTag(buf, 2, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, uint64(nsec))
+	}
+	return buf
+}
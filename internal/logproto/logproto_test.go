@@ -0,0 +1,136 @@
+package logproto
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestPushRequestMarshalRoundTrip(t *testing.T) {
+	ts := time.Unix(1700000000, 123000000)
+	req := &PushRequest{
+		Streams: []StreamAdapter{{
+			Labels: `{service="api"}`,
+			Entries: []EntryAdapter{{
+				Timestamp: ts,
+				Line:      "hello",
+				StructuredMetadata: []LabelPairAdapter{
+					{Name: "trace_id", Value: "abc123"},
+				},
+			}},
+		}},
+	}
+
+	rest := req.Marshal()
+
+	rest = consumeTag(t, rest, 1, protowire.BytesType)
+	streamBytes, rest := consumeBytes(t, rest)
+	if len(rest) != 0 {
+		t.Fatalf("unexpected trailing bytes after stream: %d", len(rest))
+	}
+
+	streamBytes = consumeTag(t, streamBytes, 1, protowire.BytesType)
+	labels, streamBytes := consumeString(t, streamBytes)
+	if labels != req.Streams[0].Labels {
+		t.Errorf("labels = %q, want %q", labels, req.Streams[0].Labels)
+	}
+
+	streamBytes = consumeTag(t, streamBytes, 2, protowire.BytesType)
+	entryBytes, streamBytes := consumeBytes(t, streamBytes)
+	if len(streamBytes) != 0 {
+		t.Fatalf("unexpected trailing bytes after entry: %d", len(streamBytes))
+	}
+
+	entryBytes = consumeTag(t, entryBytes, 1, protowire.BytesType)
+	tsBytes, entryBytes := consumeBytes(t, entryBytes)
+
+	tsBytes = consumeTag(t, tsBytes, 1, protowire.VarintType)
+	sec, tsBytes := consumeVarint(t, tsBytes)
+	if int64(sec) != ts.Unix() {
+		t.Errorf("seconds = %d, want %d", sec, ts.Unix())
+	}
+	tsBytes = consumeTag(t, tsBytes, 2, protowire.VarintType)
+	nsec, _ := consumeVarint(t, tsBytes)
+	if int(nsec) != ts.Nanosecond() {
+		t.Errorf("nanos = %d, want %d", nsec, ts.Nanosecond())
+	}
+
+	entryBytes = consumeTag(t, entryBytes, 2, protowire.BytesType)
+	line, entryBytes := consumeString(t, entryBytes)
+	if line != "hello" {
+		t.Errorf("line = %q, want %q", line, "hello")
+	}
+
+	entryBytes = consumeTag(t, entryBytes, 3, protowire.BytesType)
+	mdBytes, entryBytes := consumeBytes(t, entryBytes)
+	if len(entryBytes) != 0 {
+		t.Fatalf("unexpected trailing bytes after structured metadata: %d", len(entryBytes))
+	}
+
+	mdBytes = consumeTag(t, mdBytes, 1, protowire.BytesType)
+	name, mdBytes := consumeString(t, mdBytes)
+	if name != "trace_id" {
+		t.Errorf("name = %q, want %q", name, "trace_id")
+	}
+	mdBytes = consumeTag(t, mdBytes, 2, protowire.BytesType)
+	value, _ := consumeString(t, mdBytes)
+	if value != "abc123" {
+		t.Errorf("value = %q, want %q", value, "abc123")
+	}
+}
+
+func TestMarshalTimestampOmitsZeroFields(t *testing.T) {
+	if buf := marshalTimestamp(time.Unix(0, 0).UTC()); len(buf) != 0 {
+		t.Errorf("marshalTimestamp(zero) = %x, want empty", buf)
+	}
+
+	buf := marshalTimestamp(time.Unix(5, 0).UTC())
+	buf = consumeTag(t, buf, 1, protowire.VarintType)
+	sec, buf := consumeVarint(t, buf)
+	if sec != 5 {
+		t.Errorf("seconds = %d, want 5", sec)
+	}
+	if len(buf) != 0 {
+		t.Errorf("unexpected trailing bytes: %d", len(buf))
+	}
+}
+
+func consumeTag(t *testing.T, data []byte, wantNum protowire.Number, wantType protowire.Type) []byte {
+	t.Helper()
+	num, typ, n := protowire.ConsumeTag(data)
+	if n < 0 {
+		t.Fatalf("ConsumeTag: %v", protowire.ParseError(n))
+	}
+	if num != wantNum || typ != wantType {
+		t.Fatalf("field = (%d, %d), want (%d, %d)", num, typ, wantNum, wantType)
+	}
+	return data[n:]
+}
+
+func consumeBytes(t *testing.T, data []byte) ([]byte, []byte) {
+	t.Helper()
+	b, n := protowire.ConsumeBytes(data)
+	if n < 0 {
+		t.Fatalf("ConsumeBytes: %v", protowire.ParseError(n))
+	}
+	return b, data[n:]
+}
+
+func consumeString(t *testing.T, data []byte) (string, []byte) {
+	t.Helper()
+	s, n := protowire.ConsumeString(data)
+	if n < 0 {
+		t.Fatalf("ConsumeString: %v", protowire.ParseError(n))
+	}
+	return s, data[n:]
+}
+
+func consumeVarint(t *testing.T, data []byte) (uint64, []byte) {
+	t.Helper()
+	v, n := protowire.ConsumeVarint(data)
+	if n < 0 {
+		t.Fatalf("ConsumeVarint: %v", protowire.ParseError(n))
+	}
+	return v, data[n:]
+}
@@ -0,0 +1,44 @@
+// Package retry holds the exponential-backoff-with-jitter retry loop shared
+// by the batcher's live flush path and the spool's recovery replay path, so
+// replayed entries get the same backoff schedule as newly-queued ones (each
+// caller passes its own retry count).
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultMaxAttempts is the number of retries used where a caller has no
+// configured retry count of its own (e.g. spool replay).
+const DefaultMaxAttempts = 5
+
+// Do calls fn, retrying up to maxAttempts times with exponential backoff and
+// jitter between attempts. It returns the last error if every attempt fails.
+func Do(maxAttempts int, fn func() error) error {
+	var err error
+	for attempt := 0; attempt <= maxAttempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		time.Sleep(Backoff(attempt))
+	}
+	return err
+}
+
+// Backoff returns the delay before retry attempt (0-indexed): a base of 100ms
+// doubling per attempt and capped at 30s, plus up to 50% random jitter on
+// top of that base.
+func Backoff(attempt int) time.Duration {
+	if attempt > 9 {
+		attempt = 9 // 100ms<<9 is already over the cap; larger shifts overflow
+	}
+	base := 100 * time.Millisecond * time.Duration(1<<uint(attempt))
+	if base > 30*time.Second {
+		base = 30 * time.Second
+	}
+	return base + time.Duration(rand.Int63n(int64(base/2)+1))
+}
@@ -1,15 +1,16 @@
 package lokilogger
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"math"
+	"context"
 	"net/http"
+	"os"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/techpartners-asia/lokilogger/sink"
+	"github.com/techpartners-asia/lokilogger/spool"
 )
 
 // LogEntry represents a single log entry with timestamp and message
@@ -29,12 +30,14 @@ type Stream struct {
 	Values [][]string        `json:"values"`
 }
 
-// Logger handles communication with Loki and local logging
+// Logger is a thin wrapper around a *zap.Logger built from a Loki
+// zapcore.Core. It exists so existing callers that log through
+// Info/Error/Debug/Warn keep working unchanged; new users can skip this
+// type entirely and build their own zap.Logger with zap.New(NewCore(cfg)),
+// getting full zap semantics (sugar, With, sampling, hooks) for free.
 type Logger struct {
-	baseURL    string
-	httpClient *http.Client
-	logger     *zap.Logger
-	service    string
+	logger *zap.Logger
+	core   *Core
 }
 
 // Config holds the configuration for the logger
@@ -42,215 +45,144 @@ type Config struct {
 	BaseURL     string
 	Environment string
 	Service     string
+
+	// Batch controls how entries are buffered and flushed to Loki. See
+	// BatchConfig for defaults.
+	Batch BatchConfig
+
+	// PushFormat selects the wire format used to push batches. Defaults to FormatJSON.
+	PushFormat PushFormat
+
+	// HTTPClient, if set, is used as-is for every request to Loki.
+	HTTPClient *http.Client
+
+	// Transport is used to build the default HTTPClient when HTTPClient isn't set.
+	Transport http.RoundTripper
+
+	// TenantID, if set, is sent as X-Scope-OrgID on every request.
+	TenantID string
+
+	// BasicAuth, if set, is applied to every request.
+	BasicAuth *BasicAuth
+
+	// BearerToken, if set, is sent as a static bearer token on every request.
+	BearerToken string
+
+	// BearerTokenFile, if set (and BearerToken is not), is read on startup
+	// and re-read every BearerTokenRefresh to pick up rotated tokens.
+	BearerTokenFile string
+
+	// BearerTokenRefresh controls how often BearerTokenFile is re-read. Defaults to 30s.
+	BearerTokenRefresh time.Duration
+
+	// Headers are set on every request, after TenantID/BasicAuth/BearerToken.
+	Headers map[string]string
+
+	// Sink, if set, replaces the default Loki HTTP push sink — e.g.
+	// sink.NewKafka(...) to buffer through Kafka instead.
+	Sink sink.Sink
+
+	// Spool, if Spool.Dir is set, wraps the sink with an on-disk write-ahead
+	// log so queued batches survive a crash or a long outage. See
+	// SpoolConfig for field documentation.
+	Spool SpoolConfig
 }
 
 // New creates a new Logger instance
 func New(config Config) (*Logger, error) {
-	zapConfig := zap.NewProductionConfig()
-	zapConfig.OutputPaths = []string{"stdout"}
-	zapConfig.Encoding = "json"
-
-	logger, err := zapConfig.Build()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create logger: %w", err)
-	}
+	core := newLokiCore(CoreConfig{
+		BaseURL:            config.BaseURL,
+		Environment:        config.Environment,
+		Service:            config.Service,
+		LevelEnabler:       zap.DebugLevel, // Debug is sent to Loki too
+		Batch:              config.Batch,
+		PushFormat:         config.PushFormat,
+		HTTPClient:         config.HTTPClient,
+		Transport:          config.Transport,
+		TenantID:           config.TenantID,
+		BasicAuth:          config.BasicAuth,
+		BearerToken:        config.BearerToken,
+		BearerTokenFile:    config.BearerTokenFile,
+		BearerTokenRefresh: config.BearerTokenRefresh,
+		Headers:            config.Headers,
+		Sink:               config.Sink,
+		Spool:              config.Spool,
+	})
+
+	// Loki delivery is batched and best-effort, so it shouldn't be the only
+	// place entries end up: tee in a JSON-to-stdout core too, matching the
+	// console output operators already get from container log collection.
+	consoleCore := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(os.Stdout),
+		zap.InfoLevel,
+	)
 
 	return &Logger{
-		baseURL: config.BaseURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		logger: logger.With(
+		logger: zap.New(
+			zapcore.NewTee(core, consoleCore),
+			zap.AddCaller(),
+			zap.AddStacktrace(zap.ErrorLevel),
+		).With(
 			zap.String("service", config.Service),
 			zap.String("environment", config.Environment),
 		),
-		service: config.Service,
+		core: core,
 	}, nil
 }
 
+// Flush blocks until all entries queued so far have been pushed to Loki, or
+// ctx is done.
+func (l *Logger) Flush(ctx context.Context) error {
+	return l.core.Flush(ctx)
+}
+
+// Close drains the queue and stops the background flush loop. The Logger
+// must not be used after Close returns.
+func (l *Logger) Close() error {
+	return l.core.Close()
+}
+
+// SpoolMetrics returns the on-disk write-ahead spool's current disk usage,
+// and false if Config.Spool.Dir wasn't set.
+func (l *Logger) SpoolMetrics() (spool.Metrics, bool) {
+	return l.core.SpoolMetrics()
+}
+
+// Dropped returns the number of entries discarded because the queue was
+// full, per Config.Batch.QueuePolicy.
+func (l *Logger) Dropped() uint64 {
+	return l.core.Dropped()
+}
+
+// Zap returns the underlying *zap.Logger, for callers (like
+// lokilogger/middleware) that need full zap semantics such as arbitrary
+// levels or Check.
+func (l *Logger) Zap() *zap.Logger {
+	return l.logger
+}
+
 // Info logs an info message and sends it to Loki
 func (l *Logger) Info(msg string, fields ...zap.Field) error {
-	entry := LogEntry{
-		Timestamp: time.Now(),
-		Message:   msg,
-		Fields:    fields,
-	}
-
-	return l.sendLog(entry)
+	l.logger.Info(msg, fields...)
+	return nil
 }
 
 // Error logs an error message and sends it to Loki
 func (l *Logger) Error(msg string, err error, fields ...zap.Field) error {
 	fields = append(fields, zap.Error(err))
-	entry := LogEntry{
-		Timestamp: time.Now(),
-		Message:   msg,
-		Fields:    fields,
-	}
-
-	return l.sendLog(entry)
+	l.logger.Error(msg, fields...)
+	return nil
 }
 
 // Debug logs a debug message and sends it to Loki
 func (l *Logger) Debug(msg string, fields ...zap.Field) error {
-	entry := LogEntry{
-		Timestamp: time.Now(),
-		Message:   msg,
-		Fields:    fields,
-	}
-
-	return l.sendLog(entry)
+	l.logger.Debug(msg, fields...)
+	return nil
 }
 
 // Warn logs a warning message and sends it to Loki
 func (l *Logger) Warn(msg string, fields ...zap.Field) error {
-	entry := LogEntry{
-		Timestamp: time.Now(),
-		Message:   msg,
-		Fields:    fields,
-	}
-
-	return l.sendLog(entry)
-}
-
-// fieldsToMap converts Zap fields to a map[string]string
-func fieldsToMap(fields []zap.Field) map[string]string {
-	result := make(map[string]string)
-	for _, field := range fields {
-		switch field.Type {
-		case zapcore.StringType:
-			result[field.Key] = field.String
-		case zapcore.Int64Type:
-			result[field.Key] = fmt.Sprintf("%d", field.Integer)
-		case zapcore.Int32Type:
-			result[field.Key] = fmt.Sprintf("%d", int32(field.Integer))
-		case zapcore.Int16Type:
-			result[field.Key] = fmt.Sprintf("%d", int16(field.Integer))
-		case zapcore.Int8Type:
-			result[field.Key] = fmt.Sprintf("%d", int8(field.Integer))
-		case zapcore.Uint64Type:
-			result[field.Key] = fmt.Sprintf("%d", uint64(field.Integer))
-		case zapcore.Uint32Type:
-			result[field.Key] = fmt.Sprintf("%d", uint32(field.Integer))
-		case zapcore.Uint16Type:
-			result[field.Key] = fmt.Sprintf("%d", uint16(field.Integer))
-		case zapcore.Uint8Type:
-			result[field.Key] = fmt.Sprintf("%d", uint8(field.Integer))
-		case zapcore.Float64Type:
-			result[field.Key] = fmt.Sprintf("%.1f", math.Float64frombits(uint64(field.Integer)))
-		case zapcore.Float32Type:
-			result[field.Key] = fmt.Sprintf("%.1f", math.Float32frombits(uint32(field.Integer)))
-		case zapcore.BoolType:
-			result[field.Key] = fmt.Sprintf("%v", field.Integer == 1)
-		case zapcore.DurationType:
-			result[field.Key] = time.Duration(field.Integer).String()
-		case zapcore.TimeType:
-			if field.Interface != nil {
-				result[field.Key] = time.Unix(0, field.Integer).In(field.Interface.(*time.Location)).String()
-			} else {
-				result[field.Key] = time.Unix(0, field.Integer).String()
-			}
-		case zapcore.TimeFullType:
-			result[field.Key] = field.Interface.(time.Time).String()
-		case zapcore.ErrorType:
-			result[field.Key] = field.Interface.(error).Error()
-		case zapcore.StringerType:
-			result[field.Key] = field.Interface.(fmt.Stringer).String()
-		case zapcore.ReflectType:
-			result[field.Key] = fmt.Sprintf("%v", field.Interface)
-		case zapcore.ArrayMarshalerType:
-			result[field.Key] = fmt.Sprintf("%v", field.Interface)
-		case zapcore.ObjectMarshalerType:
-			result[field.Key] = fmt.Sprintf("%v", field.Interface)
-		case zapcore.InlineMarshalerType:
-			result[field.Key] = fmt.Sprintf("%v", field.Interface)
-		case zapcore.BinaryType:
-			result[field.Key] = fmt.Sprintf("%x", field.Interface.([]byte))
-		case zapcore.ByteStringType:
-			result[field.Key] = fmt.Sprintf("%x", field.Interface.([]byte))
-		case zapcore.Complex128Type:
-			result[field.Key] = fmt.Sprintf("%v", field.Interface.(complex128))
-		case zapcore.Complex64Type:
-			result[field.Key] = fmt.Sprintf("%v", field.Interface.(complex64))
-		case zapcore.UintptrType:
-			result[field.Key] = fmt.Sprintf("%d", uintptr(field.Integer))
-		case zapcore.NamespaceType:
-			// Skip namespace fields as they don't have a direct string representation
-		case zapcore.SkipType:
-			// Skip skip fields
-		}
-	}
-	return result
-}
-
-// sendLog sends a log entry to Loki
-func (l *Logger) sendLog(entry LogEntry) error {
-	// Create structured log entry
-	logger := l.logger.With(entry.Fields...)
-
-	// Log locally using Zap
-	logger.Info(entry.Message)
-
-	// Prepare Loki payload
-
-	// Format the complete log message with all fields
-	var messageBuilder bytes.Buffer
-	messageBuilder.WriteString(entry.Message)
-	if len(entry.Fields) > 0 {
-		messageBuilder.WriteString(" | ")
-		for i, field := range entry.Fields {
-			if i > 0 {
-				messageBuilder.WriteString(" ")
-			}
-			messageBuilder.WriteString(field.Key)
-			messageBuilder.WriteString("=")
-			messageBuilder.WriteString(fieldsToMap([]zap.Field{field})[field.Key])
-		}
-	}
-
-	payload := LokiPayload{
-		Streams: []Stream{
-			{
-				Stream: map[string]string{
-					"source": l.service,
-				},
-				Values: [][]string{
-					{
-						fmt.Sprintf("%d", entry.Timestamp.UnixNano()),
-						messageBuilder.String(),
-					},
-				},
-			},
-		},
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		logger.Error("Failed to marshal payload", zap.Error(err))
-		return fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", l.baseURL+"/loki/api/v1/push", bytes.NewBuffer(jsonData))
-	if err != nil {
-		logger.Error("Failed to create request", zap.Error(err))
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := l.httpClient.Do(req)
-	if err != nil {
-		logger.Error("Failed to send request", zap.Error(err))
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		logger.Error("Unexpected status code",
-			zap.Int("status_code", resp.StatusCode),
-			zap.String("status", resp.Status))
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
+	l.logger.Warn(msg, fields...)
 	return nil
 }
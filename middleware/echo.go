@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/techpartners-asia/lokilogger"
+)
+
+// Echo adapts the middleware for echo.Echo.Use, using echo's route template
+// (c.Path()) instead of the raw path when no WithRouteFunc is given.
+func Echo(l *lokilogger.Logger, opts ...Option) echo.MiddlewareFunc {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if o.skipPaths[req.URL.Path] {
+				return next(c)
+			}
+
+			start := time.Now()
+
+			requestID := req.Header.Get("X-Request-ID")
+			if requestID == "" {
+				requestID = newRequestID()
+			}
+			c.Response().Header().Set("X-Request-ID", requestID)
+
+			route := c.Path()
+			if o.routeFunc != nil {
+				route = o.routeFunc(req)
+			}
+
+			defer func() {
+				if p := recover(); p != nil {
+					fields := buildFields(req, requestID, route, http.StatusInternalServerError, start, int(c.Response().Size))
+					logPanic(l, fields, p)
+					panic(p)
+				}
+			}()
+
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				if he, ok := err.(*echo.HTTPError); ok {
+					status = he.Code
+				} else if status < http.StatusBadRequest {
+					status = http.StatusInternalServerError
+				}
+			}
+
+			logRequest(l, buildFields(req, requestID, route, status, start, int(c.Response().Size)))
+
+			return err
+		}
+	}
+}
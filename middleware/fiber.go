@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/techpartners-asia/lokilogger"
+)
+
+// Fiber adapts the middleware for fiber.App.Use, using fiber's own route
+// template (c.Route().Path) instead of the raw path. WithRouteFunc is
+// ignored here: fiber has no *http.Request for a net/http-based
+// WithRouteFunc to inspect.
+func Fiber(l *lokilogger.Logger, opts ...Option) fiber.Handler {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		if o.skipPaths[path] {
+			return c.Next()
+		}
+
+		start := time.Now()
+
+		requestID := c.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Set("X-Request-ID", requestID)
+
+		route := path
+		if r := c.Route(); r != nil {
+			route = r.Path
+		}
+
+		defer func() {
+			fields := requestFields{
+				requestID: requestID,
+				method:    c.Method(),
+				path:      path,
+				route:     route,
+				status:    c.Response().StatusCode(),
+				duration:  time.Since(start),
+				remoteIP:  c.IP(),
+				userAgent: string(c.Request().Header.UserAgent()),
+				traceID:   traceIDFromCtx(c.UserContext()),
+				bytes:     len(c.Response().Body()),
+			}
+
+			if p := recover(); p != nil {
+				logPanic(l, fields, p)
+				panic(p)
+			}
+
+			logRequest(l, fields)
+		}()
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/techpartners-asia/lokilogger"
+)
+
+// Gin adapts the middleware for gin.Engine.Use, using gin's route template
+// (c.FullPath()) instead of the raw path when no WithRouteFunc is given.
+func Gin(l *lokilogger.Logger, opts ...Option) gin.HandlerFunc {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(c *gin.Context) {
+		if o.skipPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Header("X-Request-ID", requestID)
+
+		defer func() {
+			route := c.FullPath()
+			if o.routeFunc != nil {
+				route = o.routeFunc(c.Request)
+			}
+
+			size := c.Writer.Size()
+			if size < 0 {
+				// Size() is -1 until the first Write call (e.g. a bare
+				// WriteHeader for a 204 or redirect never writes a body).
+				size = 0
+			}
+			fields := buildFields(c.Request, requestID, route, c.Writer.Status(), start, size)
+
+			if p := recover(); p != nil {
+				logPanic(l, fields, p)
+				panic(p)
+			}
+
+			logRequest(l, fields)
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,226 @@
+// Package middleware emits one structured Loki log entry per HTTP request,
+// via a plain net/http middleware plus thin Gin/Echo/Fiber adapters that all
+// funnel into the same field set and level selection.
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/techpartners-asia/lokilogger"
+)
+
+// Option configures the middleware.
+type Option func(*options)
+
+type options struct {
+	routeFunc func(*http.Request) string
+	skipPaths map[string]bool
+}
+
+// WithRouteFunc sets a function that extracts the route template (e.g.
+// "/users/:id") from a request. Defaults to the request path, since plain
+// net/http has no notion of route templates.
+func WithRouteFunc(f func(*http.Request) string) Option {
+	return func(o *options) { o.routeFunc = f }
+}
+
+// WithSkipPaths excludes the given exact paths (e.g. "/healthz") from logging.
+func WithSkipPaths(paths ...string) Option {
+	return func(o *options) {
+		if o.skipPaths == nil {
+			o.skipPaths = make(map[string]bool, len(paths))
+		}
+		for _, p := range paths {
+			o.skipPaths[p] = true
+		}
+	}
+}
+
+// Handler wraps next to emit a single structured log entry per request via
+// l, with a level chosen from the response status (5xx -> Error, 4xx ->
+// Warn, else Info). Panics are recovered, logged with a stack trace, and
+// re-panicked so upstream recovery middleware still sees them.
+func Handler(l *lokilogger.Logger, opts ...Option) func(http.Handler) http.Handler {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.skipPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+
+			requestID := r.Header.Get("X-Request-ID")
+			if requestID == "" {
+				requestID = newRequestID()
+			}
+			w.Header().Set("X-Request-ID", requestID)
+
+			route := r.URL.Path
+			if o.routeFunc != nil {
+				route = o.routeFunc(r)
+			}
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			defer func() {
+				fields := buildFields(r, requestID, route, rec.status, start, rec.written)
+
+				if p := recover(); p != nil {
+					logPanic(l, fields, p)
+					panic(p)
+				}
+
+				logRequest(l, fields)
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// statusRecorder wraps http.ResponseWriter to capture the status code and
+// bytes written, since net/http doesn't expose either after the fact.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.written += n
+	return n, err
+}
+
+// requestFields is the common field set every adapter logs, regardless of
+// which router produced the request.
+type requestFields struct {
+	requestID string
+	method    string
+	path      string
+	route     string
+	status    int
+	duration  time.Duration
+	remoteIP  string
+	userAgent string
+	traceID   string
+	bytes     int
+}
+
+// buildFields assembles the common field set from a net/http request;
+// framework adapters (Gin, Echo) reuse it since they all carry one.
+func buildFields(r *http.Request, requestID, route string, status int, start time.Time, bytesWritten int) requestFields {
+	return requestFields{
+		requestID: requestID,
+		method:    r.Method,
+		path:      r.URL.Path,
+		route:     route,
+		status:    status,
+		duration:  time.Since(start),
+		remoteIP:  remoteIP(r),
+		userAgent: r.UserAgent(),
+		traceID:   traceIDFromContext(r),
+		bytes:     bytesWritten,
+	}
+}
+
+func (f requestFields) zapFields() []zap.Field {
+	fields := []zap.Field{
+		zap.String("request_id", f.requestID),
+		zap.String("method", f.method),
+		zap.String("path", f.path),
+		zap.String("route", f.route),
+		zap.Int("status", f.status),
+		zap.Float64("duration_ms", float64(f.duration.Microseconds())/1000),
+		zap.String("remote_ip", f.remoteIP),
+		zap.String("user_agent", f.userAgent),
+		zap.Int("bytes", f.bytes),
+	}
+	if f.traceID != "" {
+		fields = append(fields, zap.String("trace_id", f.traceID))
+	}
+	return fields
+}
+
+// logRequest emits the per-request entry at a level chosen from the status code.
+func logRequest(l *lokilogger.Logger, f requestFields) {
+	switch {
+	case f.status >= http.StatusInternalServerError:
+		l.Zap().Error("http request", f.zapFields()...)
+	case f.status >= http.StatusBadRequest:
+		l.Zap().Warn("http request", f.zapFields()...)
+	default:
+		l.Zap().Info("http request", f.zapFields()...)
+	}
+}
+
+// logPanic logs a recovered panic with its stack trace before the caller re-panics.
+func logPanic(l *lokilogger.Logger, f requestFields, recovered any) {
+	fields := append(f.zapFields(),
+		zap.Any("panic", recovered),
+		zap.String("stacktrace", string(debug.Stack())),
+	)
+	l.Zap().Error("panic recovered", fields...)
+}
+
+// newRequestID generates a request ID for requests that don't already carry
+// an X-Request-ID header.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// remoteIP prefers X-Forwarded-For (first entry) and falls back to RemoteAddr.
+func remoteIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		if i := strings.IndexByte(xff, ','); i != -1 {
+			return strings.TrimSpace(xff[:i])
+		}
+		return strings.TrimSpace(xff)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// traceIDFromContext reads the OpenTelemetry trace ID out of the request
+// context, if a span is present.
+func traceIDFromContext(r *http.Request) string {
+	return traceIDFromCtx(r.Context())
+}
+
+// traceIDFromCtx reads the OpenTelemetry trace ID out of ctx, if a span is
+// present. Used directly by adapters (like Fiber) that carry a
+// context.Context but no *http.Request.
+func traceIDFromCtx(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,280 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/techpartners-asia/lokilogger/internal/logproto"
+)
+
+// PushFormat selects the wire format HTTPSink uses to push batches.
+type PushFormat int
+
+const (
+	// FormatJSON pushes batches using the plain JSON structure documented by
+	// Loki's HTTP push API. This is the default.
+	FormatJSON PushFormat = iota
+	// FormatProtobuf pushes batches as a Snappy-compressed logproto.PushRequest,
+	// matching Loki's efficient ingestion path.
+	FormatProtobuf
+)
+
+// BasicAuth holds HTTP basic auth credentials for the Loki push endpoint.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// defaultBearerTokenRefresh is how often BearerTokenFile is re-read when no
+// explicit HTTPConfig.BearerTokenRefresh is set.
+const defaultBearerTokenRefresh = 30 * time.Second
+
+// HTTPConfig configures an HTTPSink.
+type HTTPConfig struct {
+	BaseURL string
+
+	// HTTPClient, if set, is used as-is for every request.
+	HTTPClient *http.Client
+	// Transport builds the default HTTPClient when HTTPClient isn't set.
+	Transport http.RoundTripper
+
+	// Format selects JSON or Snappy-compressed protobuf. Defaults to FormatJSON.
+	Format PushFormat
+
+	// TenantID, if set, is sent as X-Scope-OrgID on every request.
+	TenantID string
+	// BasicAuth, if set, is applied to every request.
+	BasicAuth *BasicAuth
+	// BearerToken, if set, is sent as a static bearer token on every request.
+	BearerToken string
+	// BearerTokenFile, if set (and BearerToken is not), is read on startup
+	// and re-read every BearerTokenRefresh to pick up rotated tokens.
+	BearerTokenFile string
+	// BearerTokenRefresh controls how often BearerTokenFile is re-read. Defaults to 30s.
+	BearerTokenRefresh time.Duration
+	// Headers are set on every request, after TenantID/BasicAuth/BearerToken.
+	Headers map[string]string
+
+	// OnError, if set, is called when reading BearerTokenFile fails.
+	OnError func(error)
+}
+
+// HTTPSink pushes streams to Loki's /loki/api/v1/push endpoint.
+type HTTPSink struct {
+	cfg         HTTPConfig
+	httpClient  *http.Client
+	bearerToken atomic.Value // string
+	authStop    chan struct{}
+}
+
+// NewHTTP builds an HTTPSink from cfg.
+func NewHTTP(cfg HTTPConfig) *HTTPSink {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second, Transport: cfg.Transport}
+	}
+
+	s := &HTTPSink{
+		cfg:        cfg,
+		httpClient: cfg.HTTPClient,
+	}
+	s.startAuth()
+
+	return s
+}
+
+// Push implements Sink.
+func (s *HTTPSink) Push(ctx context.Context, streams []Stream) error {
+	switch s.cfg.Format {
+	case FormatProtobuf:
+		return s.pushProtobuf(ctx, streams)
+	default:
+		return s.pushJSON(ctx, streams)
+	}
+}
+
+// Close implements Sink, stopping the bearer-token-file watcher if one is running.
+func (s *HTTPSink) Close() error {
+	if s.authStop != nil {
+		close(s.authStop)
+	}
+	return nil
+}
+
+func (s *HTTPSink) pushJSON(ctx context.Context, streams []Stream) error {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][]string        `json:"values"`
+	}
+	type payload struct {
+		Streams []jsonStream `json:"streams"`
+	}
+
+	p := payload{Streams: make([]jsonStream, 0, len(streams))}
+	for _, st := range streams {
+		values := make([][]string, 0, len(st.Values))
+		for _, v := range st.Values {
+			values = append(values, []string{v[0], v[1]})
+		}
+		p.Streams = append(p.Streams, jsonStream{Stream: st.Labels, Values: values})
+	}
+
+	jsonData, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL+"/loki/api/v1/push", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.applyAuth(req)
+
+	return s.do(req)
+}
+
+// pushProtobuf encodes streams as a logproto.PushRequest, Snappy-compresses
+// it, and POSTs it with the content type/encoding Loki expects on its
+// efficient ingestion path.
+func (s *HTTPSink) pushProtobuf(ctx context.Context, streams []Stream) error {
+	pushReq := &logproto.PushRequest{Streams: make([]logproto.StreamAdapter, 0, len(streams))}
+	for _, st := range streams {
+		adapter := logproto.StreamAdapter{
+			Labels:  formatLabels(st.Labels),
+			Entries: make([]logproto.EntryAdapter, 0, len(st.Values)),
+		}
+		for _, v := range st.Values {
+			tsNano, err := strconv.ParseInt(v[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse entry timestamp: %w", err)
+			}
+			adapter.Entries = append(adapter.Entries, logproto.EntryAdapter{
+				Timestamp: time.Unix(0, tsNano),
+				Line:      v[1],
+			})
+		}
+		pushReq.Streams = append(pushReq.Streams, adapter)
+	}
+
+	compressed := snappy.Encode(nil, pushReq.Marshal())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL+"/loki/api/v1/push", bytes.NewReader(compressed))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	s.applyAuth(req)
+
+	return s.do(req)
+}
+
+func (s *HTTPSink) do(req *http.Request) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// startAuth wires up whatever cfg specifies: a static bearer token is stored
+// once, a BearerTokenFile is read immediately and then refreshed on a
+// ticker until Close is called.
+func (s *HTTPSink) startAuth() {
+	switch {
+	case s.cfg.BearerToken != "":
+		s.bearerToken.Store(s.cfg.BearerToken)
+	case s.cfg.BearerTokenFile != "":
+		s.authStop = make(chan struct{})
+		s.reloadBearerTokenFile()
+		go s.watchBearerTokenFile()
+	}
+}
+
+func (s *HTTPSink) watchBearerTokenFile() {
+	refresh := s.cfg.BearerTokenRefresh
+	if refresh <= 0 {
+		refresh = defaultBearerTokenRefresh
+	}
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.reloadBearerTokenFile()
+		case <-s.authStop:
+			return
+		}
+	}
+}
+
+func (s *HTTPSink) reloadBearerTokenFile() {
+	data, err := os.ReadFile(s.cfg.BearerTokenFile)
+	if err != nil {
+		if s.cfg.OnError != nil {
+			s.cfg.OnError(err)
+		}
+		return
+	}
+	s.bearerToken.Store(strings.TrimSpace(string(data)))
+}
+
+// applyAuth sets the tenant, basic auth, bearer token, and any extra headers
+// configured on req.
+func (s *HTTPSink) applyAuth(req *http.Request) {
+	if s.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.cfg.TenantID)
+	}
+	if s.cfg.BasicAuth != nil {
+		req.SetBasicAuth(s.cfg.BasicAuth.Username, s.cfg.BasicAuth.Password)
+	}
+	if tok, ok := s.bearerToken.Load().(string); ok && tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// formatLabels renders a label set as a LogQL selector (e.g.
+// `{service="api", level="info"}`), the format logproto.Stream.Labels expects.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(k)
+		sb.WriteString(`="`)
+		sb.WriteString(labels[k])
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
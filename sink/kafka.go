@@ -0,0 +1,126 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/techpartners-asia/lokilogger/internal/logproto"
+)
+
+// KafkaConfig configures a KafkaSink.
+type KafkaConfig struct {
+	Brokers []string
+	Topic   string
+	// Format selects JSON or protobuf encoding for each message. Defaults to FormatJSON.
+	Format PushFormat
+
+	// Writer, if set, is used as-is instead of building one from Brokers/Topic.
+	Writer *kafka.Writer
+}
+
+// KafkaSink writes batches to Kafka instead of pushing them to Loki
+// directly. This decouples the application from Loki's availability: if
+// Loki is down for hours the app keeps running and a companion consumer (or
+// Promtail's Kafka target) replays the messages to Loki later.
+type KafkaSink struct {
+	cfg    KafkaConfig
+	writer *kafka.Writer
+}
+
+// NewKafka builds a KafkaSink from cfg.
+func NewKafka(cfg KafkaConfig) *KafkaSink {
+	writer := cfg.Writer
+	if writer == nil {
+		writer = &kafka.Writer{
+			Addr:     kafka.TCP(cfg.Brokers...),
+			Topic:    cfg.Topic,
+			Balancer: &kafka.Hash{},
+		}
+	}
+
+	return &KafkaSink{cfg: cfg, writer: writer}
+}
+
+// Push implements Sink, writing each stream as its own Kafka message keyed
+// by a hash of its label set, so a partition consumer sees ordered entries
+// per stream.
+func (k *KafkaSink) Push(ctx context.Context, streams []Stream) error {
+	messages := make([]kafka.Message, 0, len(streams))
+	for _, s := range streams {
+		value, err := k.encode(s)
+		if err != nil {
+			return fmt.Errorf("failed to encode stream: %w", err)
+		}
+		messages = append(messages, kafka.Message{
+			Key:   streamKey(s.Labels),
+			Value: value,
+			Time:  time.Now(),
+		})
+	}
+
+	if err := k.writer.WriteMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to write messages to kafka: %w", err)
+	}
+
+	return nil
+}
+
+// Close implements Sink.
+func (k *KafkaSink) Close() error {
+	return k.writer.Close()
+}
+
+func (k *KafkaSink) encode(s Stream) ([]byte, error) {
+	switch k.cfg.Format {
+	case FormatProtobuf:
+		return k.encodeProtobuf(s)
+	default:
+		return k.encodeJSON(s)
+	}
+}
+
+func (k *KafkaSink) encodeJSON(s Stream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][]string        `json:"values"`
+	}
+	values := make([][]string, 0, len(s.Values))
+	for _, v := range s.Values {
+		values = append(values, []string{v[0], v[1]})
+	}
+	return json.Marshal(jsonStream{Stream: s.Labels, Values: values})
+}
+
+func (k *KafkaSink) encodeProtobuf(s Stream) ([]byte, error) {
+	adapter := logproto.StreamAdapter{
+		Labels:  formatLabels(s.Labels),
+		Entries: make([]logproto.EntryAdapter, 0, len(s.Values)),
+	}
+	for _, v := range s.Values {
+		tsNano, err := strconv.ParseInt(v[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse entry timestamp: %w", err)
+		}
+		adapter.Entries = append(adapter.Entries, logproto.EntryAdapter{
+			Timestamp: time.Unix(0, tsNano),
+			Line:      v[1],
+		})
+	}
+
+	req := &logproto.PushRequest{Streams: []logproto.StreamAdapter{adapter}}
+	return req.Marshal(), nil
+}
+
+// streamKey hashes a label set so messages for the same stream land on the
+// same Kafka partition.
+func streamKey(labels map[string]string) []byte {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(formatLabels(labels)))
+	return []byte(strconv.FormatUint(h.Sum64(), 16))
+}
@@ -0,0 +1,19 @@
+// Package sink defines the pluggable transport a batch of Loki streams is
+// pushed through. HTTPSink talks to Loki's own push API; KafkaSink instead
+// writes to Kafka so an application keeps running (and logs get replayed
+// later) even when Loki itself is down.
+package sink
+
+import "context"
+
+// Stream is a label set and the log lines recorded under it, ready to push.
+type Stream struct {
+	Labels map[string]string
+	Values [][2]string // [unix-nano timestamp, line]
+}
+
+// Sink pushes batches of streams to wherever they're headed.
+type Sink interface {
+	Push(ctx context.Context, streams []Stream) error
+	Close() error
+}
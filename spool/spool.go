@@ -0,0 +1,414 @@
+// Package spool implements an optional on-disk write-ahead log for a
+// sink.Sink, so queued batches survive a process crash or a long Loki
+// outage instead of only ever living in memory. Wrap an existing Sink with
+// Wrap to get durability; callers see the same Sink interface either way.
+package spool
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/techpartners-asia/lokilogger/internal/retry"
+	"github.com/techpartners-asia/lokilogger/sink"
+)
+
+const (
+	defaultMaxSegmentBytes = 64 << 20 // 64MB
+	defaultMaxTotalBytes   = 1 << 30  // 1GB
+
+	segmentPrefix = "spool-"
+	segmentSuffix = ".log"
+)
+
+// Config controls the on-disk write-ahead spool.
+type Config struct {
+	// Dir is the spool directory. Spooling is disabled if Dir is empty.
+	Dir string
+
+	// MaxSegmentBytes rotates to a new segment file once the active one
+	// reaches this size. Defaults to 64MB.
+	MaxSegmentBytes int64
+
+	// MaxTotalBytes caps total spool disk usage across all segments; once
+	// exceeded, the oldest segments are evicted (and their unpushed
+	// contents lost) to bound disk usage. Defaults to 1GB.
+	MaxTotalBytes int64
+
+	// OnError, if set, is called when a recovery replay fails, and for every
+	// segment evicted (or failing to be evicted) over MaxTotalBytes.
+	OnError func(error)
+}
+
+func (c *Config) setDefaults() {
+	if c.MaxSegmentBytes <= 0 {
+		c.MaxSegmentBytes = defaultMaxSegmentBytes
+	}
+	if c.MaxTotalBytes <= 0 {
+		c.MaxTotalBytes = defaultMaxTotalBytes
+	}
+}
+
+// Metrics reports the spool's current disk usage, for operators to alert on
+// a Loki outage before the queue itself starts dropping entries.
+type Metrics struct {
+	Bytes            int64
+	Segments         int
+	OldestAgeSeconds float64
+}
+
+// segment is the file currently being appended to.
+type segment struct {
+	path    string
+	file    *os.File
+	size    int64
+	records []*recordState
+}
+
+// recordState tracks whether a single record written to a segment has been
+// confirmed delivered to inner. A segment is only safe to delete once every
+// record in it is delivered — one successful push must never be allowed to
+// paper over an earlier one that failed.
+type recordState struct {
+	delivered bool
+}
+
+// fullyDelivered reports whether every record written to seg has been
+// confirmed delivered to inner.
+func (seg *segment) fullyDelivered() bool {
+	for _, r := range seg.records {
+		if !r.delivered {
+			return false
+		}
+	}
+	return true
+}
+
+// Spool wraps a sink.Sink with a write-ahead log: every batch is appended to
+// (and fsync'd in) a segment file before the push to inner is attempted, so
+// a crash between the two loses nothing. Wrap starts replaying whatever a
+// previous process left unfinished in a background goroutine.
+type Spool struct {
+	inner sink.Sink
+	cfg   Config
+
+	mu     sync.Mutex
+	active *segment
+}
+
+// Wrap returns a sink.Sink backed by cfg.Dir, and starts a background
+// goroutine that replays any segments left over from a previous process,
+// deleting each one once it's fully delivered, without blocking the caller
+// on a possibly-still-down inner sink. Replay pushes straight to inner: it
+// does not go through the live batcher, so it bypasses QueuePolicy, the
+// Dropped counter, and BatchConfig.OnError — a failed replay is reported
+// via cfg.OnError instead. It shares only the low-level retry.Do backoff
+// helper with a live flush, not the rest of the batching pipeline.
+func Wrap(inner sink.Sink, cfg Config) (*Spool, error) {
+	cfg.setDefaults()
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool dir: %w", err)
+	}
+
+	s := &Spool{inner: inner, cfg: cfg}
+
+	if err := s.rollSegment(); err != nil {
+		return nil, fmt.Errorf("failed to open spool segment: %w", err)
+	}
+
+	go s.recover(context.Background())
+
+	return s, nil
+}
+
+// Push implements sink.Sink: it appends streams to the active segment and
+// fsyncs exactly once, then delivers to inner, retrying the delivery (not
+// the append) with the same retry.Do backoff replaySegment uses. Retrying
+// here rather than leaving it to the caller matters: a caller-side retry
+// loop wrapping the whole of Push would re-encode and re-fsync a brand new
+// WAL record on every attempt, leaving duplicate records on disk with only
+// the last one ever marked delivered — the earlier, already-superseded
+// records would then never be cleanable and would be redelivered on the
+// next recovery pass too. Each record tracks its own delivered state, so
+// one record's successful push never marks an earlier, still-failing
+// record as durable — a segment is only deleted once every record written
+// to it has been confirmed delivered.
+func (s *Spool) Push(ctx context.Context, streams []sink.Stream) error {
+	record, err := encodeRecord(streams)
+	if err != nil {
+		return fmt.Errorf("failed to encode spool record: %w", err)
+	}
+
+	s.mu.Lock()
+	if s.active.size+int64(len(record)) > s.cfg.MaxSegmentBytes {
+		if err := s.rotate(); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	if _, err := s.active.file.Write(record); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to write spool record: %w", err)
+	}
+	if err := s.active.file.Sync(); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to fsync spool segment: %w", err)
+	}
+	s.active.size += int64(len(record))
+	rec := &recordState{}
+	s.active.records = append(s.active.records, rec)
+	s.mu.Unlock()
+
+	pushErr := retry.Do(retry.DefaultMaxAttempts, func() error {
+		return s.inner.Push(ctx, streams)
+	})
+
+	s.mu.Lock()
+	if pushErr == nil {
+		rec.delivered = true
+	}
+	s.evictIfOverCap()
+	s.mu.Unlock()
+
+	return pushErr
+}
+
+// Close closes the active segment (deleting it if everything in it was
+// confirmed delivered) and closes inner.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active != nil {
+		if err := s.closeSegment(s.active); err != nil {
+			return err
+		}
+		s.active = nil
+	}
+
+	return s.inner.Close()
+}
+
+// Metrics returns the spool's current disk usage.
+func (s *Spool) Metrics() Metrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos, err := s.listSegments()
+	if err != nil {
+		return Metrics{}
+	}
+
+	var m Metrics
+	for i, info := range infos {
+		m.Bytes += info.Size()
+		if i == 0 {
+			m.OldestAgeSeconds = time.Since(info.ModTime()).Seconds()
+		}
+	}
+	m.Segments = len(infos)
+
+	return m
+}
+
+func (s *Spool) rotate() error {
+	if err := s.closeSegment(s.active); err != nil {
+		return err
+	}
+	return s.rollSegment()
+}
+
+func (s *Spool) rollSegment() error {
+	path := filepath.Join(s.cfg.Dir, fmt.Sprintf("%s%d%s", segmentPrefix, time.Now().UnixNano(), segmentSuffix))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.active = &segment{path: path, file: f}
+	return nil
+}
+
+// closeSegment closes seg's file and, if every record in it was confirmed
+// delivered, deletes it. Otherwise it's left on disk for the next recovery
+// pass to replay.
+func (s *Spool) closeSegment(seg *segment) error {
+	if err := seg.file.Close(); err != nil {
+		return fmt.Errorf("failed to close spool segment: %w", err)
+	}
+	if !seg.fullyDelivered() {
+		return nil
+	}
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove spool segment: %w", err)
+	}
+	return nil
+}
+
+// evictIfOverCap deletes the oldest segments, other than the active one,
+// until total spool usage is back under MaxTotalBytes.
+func (s *Spool) evictIfOverCap() {
+	infos, err := s.listSegments()
+	if err != nil {
+		return
+	}
+
+	var total int64
+	for _, info := range infos {
+		total += info.Size()
+	}
+
+	for _, info := range infos {
+		if total <= s.cfg.MaxTotalBytes {
+			return
+		}
+		path := filepath.Join(s.cfg.Dir, info.Name())
+		if s.active != nil && path == s.active.path {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			if s.cfg.OnError != nil {
+				s.cfg.OnError(fmt.Errorf("failed to evict spool segment %s: %w", path, err))
+			}
+			continue
+		}
+		total -= info.Size()
+		if s.cfg.OnError != nil {
+			s.cfg.OnError(fmt.Errorf("spool exceeded MaxTotalBytes: evicted %s", path))
+		}
+	}
+}
+
+// recover replays every existing segment (oldest first, skipping the
+// segment currently being written to) through inner, deleting each one as
+// soon as it's fully delivered. Segments that fail to replay are left in
+// place for the next recovery pass.
+func (s *Spool) recover(ctx context.Context) {
+	infos, err := s.listSegments()
+	if err != nil {
+		if s.cfg.OnError != nil {
+			s.cfg.OnError(fmt.Errorf("failed to list spool segments: %w", err))
+		}
+		return
+	}
+
+	for _, info := range infos {
+		path := filepath.Join(s.cfg.Dir, info.Name())
+		if s.isActive(path) {
+			continue
+		}
+		if err := s.replaySegment(ctx, path); err != nil {
+			if s.cfg.OnError != nil {
+				s.cfg.OnError(fmt.Errorf("failed to replay spool segment %s: %w", path, err))
+			}
+		}
+	}
+}
+
+func (s *Spool) isActive(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active != nil && s.active.path == path
+}
+
+// replaySegment pushes every record in path straight through inner (not the
+// live batcher — see the deviation noted on Wrap), retrying each one with
+// retry.Do, the same backoff helper batcher.flushStreams uses.
+func (s *Spool) replaySegment(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		streams, err := decodeRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("corrupt spool record: %w", err)
+		}
+		if err := retry.Do(retry.DefaultMaxAttempts, func() error {
+			return s.inner.Push(ctx, streams)
+		}); err != nil {
+			return fmt.Errorf("failed to replay record: %w", err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// listSegments returns this spool's segment files, oldest first (their
+// names sort chronologically since they're suffixed with a UnixNano).
+func (s *Spool) listSegments() ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), segmentPrefix) || !strings.HasSuffix(e.Name(), segmentSuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+
+	return infos, nil
+}
+
+// encodeRecord serializes streams as a length-prefixed JSON record.
+func encodeRecord(streams []sink.Stream) ([]byte, error) {
+	payload, err := json.Marshal(streams)
+	if err != nil {
+		return nil, err
+	}
+
+	record := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(record, uint32(len(payload)))
+	copy(record[4:], payload)
+
+	return record, nil
+}
+
+func decodeRecord(r *bufio.Reader) ([]sink.Stream, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(lenBuf[:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	var streams []sink.Stream
+	if err := json.Unmarshal(payload, &streams); err != nil {
+		return nil, err
+	}
+
+	return streams, nil
+}
@@ -0,0 +1,153 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/techpartners-asia/lokilogger/sink"
+)
+
+// fakeSink lets tests control which pushed lines fail and observe what was
+// actually (successfully) pushed.
+type fakeSink struct {
+	mu      sync.Mutex
+	attempt int
+	pushes  [][]sink.Stream
+
+	// fail, if set, is called with the 1-indexed call number; a non-nil
+	// return fails that call. Since Spool.Push now retries delivery
+	// internally (retry.DefaultMaxAttempts) before ever returning an error,
+	// this must fail every attempt for a given batch to make that batch's
+	// Push call actually fail — failing only the first global call would
+	// just be swallowed by the internal retry.
+	fail func(call int) error
+	// pushedCh, if set, receives every successfully pushed batch.
+	pushedCh chan []sink.Stream
+}
+
+func (f *fakeSink) Push(_ context.Context, streams []sink.Stream) error {
+	f.mu.Lock()
+	f.attempt++
+	call := f.attempt
+	f.mu.Unlock()
+
+	if f.fail != nil {
+		if err := f.fail(call); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	f.pushes = append(f.pushes, streams)
+	f.mu.Unlock()
+
+	if f.pushedCh != nil {
+		f.pushedCh <- streams
+	}
+	return nil
+}
+
+func (f *fakeSink) Close() error { return nil }
+
+func streamFor(line string) []sink.Stream {
+	return []sink.Stream{{
+		Labels: map[string]string{"service": "api"},
+		Values: [][2]string{{"1", line}},
+	}}
+}
+
+// TestLossOnFailThenSucceed is a regression test for a bug where a failed
+// push's record was silently treated as durable once a later record in the
+// same segment pushed successfully, so the segment got deleted with the
+// failed record's data still unpushed. A push that fails must keep its
+// record on disk until it's actually delivered, no matter what happens to
+// pushes after it.
+func TestLossOnFailThenSucceed(t *testing.T) {
+	dir := t.TempDir()
+
+	// Spool.Push retries delivery internally up to retry.DefaultMaxAttempts
+	// times before giving up, so batch1's Push call makes that many inner
+	// calls; fail all of them so batch1 genuinely fails, then let batch2
+	// through on its first (and only) call.
+	const batch1Attempts = 1 + 5 // retry.DefaultMaxAttempts
+	failFirst := &fakeSink{fail: func(call int) error {
+		if call <= batch1Attempts {
+			return errors.New("boom")
+		}
+		return nil
+	}}
+
+	sp, err := Wrap(failFirst, Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("Wrap: %v", err)
+	}
+
+	if err := sp.Push(context.Background(), streamFor("batch1")); err == nil {
+		t.Fatal("Push(batch1) unexpectedly succeeded")
+	}
+	if err := sp.Push(context.Background(), streamFor("batch2")); err != nil {
+		t.Fatalf("Push(batch2): %v", err)
+	}
+
+	if err := sp.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("spool segment was deleted even though batch1 was never delivered")
+	}
+
+	replay := &fakeSink{pushedCh: make(chan []sink.Stream, 8)}
+	if _, err := Wrap(replay, Config{Dir: dir}); err != nil {
+		t.Fatalf("Wrap (recovery): %v", err)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case streams := <-replay.pushedCh:
+			if streams[0].Values[0][1] == "batch1" {
+				return // batch1 was recovered and redelivered: not lost.
+			}
+		case <-deadline:
+			t.Fatal("batch1 was never replayed: it was lost")
+		}
+	}
+}
+
+// TestPushDeletesFullyDeliveredSegmentOnRotate confirms the non-failure
+// path still reclaims disk space once every record in a segment is
+// confirmed delivered.
+func TestPushDeletesFullyDeliveredSegmentOnRotate(t *testing.T) {
+	dir := t.TempDir()
+
+	sp, err := Wrap(&fakeSink{}, Config{Dir: dir, MaxSegmentBytes: 1})
+	if err != nil {
+		t.Fatalf("Wrap: %v", err)
+	}
+
+	if err := sp.Push(context.Background(), streamFor("one")); err != nil {
+		t.Fatalf("Push(one): %v", err)
+	}
+	// MaxSegmentBytes: 1 forces this push to rotate, closing (and deleting,
+	// since "one" was fully delivered) the prior segment.
+	if err := sp.Push(context.Background(), streamFor("two")); err != nil {
+		t.Fatalf("Push(two): %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("spool dir has %d segments, want 1 (the still-active one)", len(entries))
+	}
+}